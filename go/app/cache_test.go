@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNotModified(t *testing.T) {
+	modTime := time.Date(2026, 1, 15, 12, 0, 0, 0, time.UTC)
+	entry := &cacheEntry{etag: `"abc123"`, modTime: modTime}
+
+	cases := []struct {
+		name   string
+		header string
+		value  string
+		want   bool
+	}{
+		{"if-none-match matches etag", "If-None-Match", `"abc123"`, true},
+		{"if-none-match mismatches etag", "If-None-Match", `"other"`, false},
+		{"if-modified-since before entry", "If-Modified-Since", modTime.Add(-time.Hour).Format(http.TimeFormat), false},
+		{"if-modified-since equal to entry", "If-Modified-Since", modTime.Format(http.TimeFormat), true},
+		{"if-modified-since after entry", "If-Modified-Since", modTime.Add(time.Hour).Format(http.TimeFormat), true},
+		{"no conditional headers", "", "", false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/items", nil)
+			if tc.header != "" {
+				r.Header.Set(tc.header, tc.value)
+			}
+			if got := notModified(r, entry); got != tc.want {
+				t.Errorf("notModified() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLRUCacheEvictsOldestAtCapacity(t *testing.T) {
+	c := newLRUCache(2)
+	c.set("/a", &cacheEntry{})
+	c.set("/b", &cacheEntry{})
+	c.set("/c", &cacheEntry{})
+
+	if _, ok := c.get("/a"); ok {
+		t.Error("expected /a to be evicted once capacity was exceeded")
+	}
+	if _, ok := c.get("/b"); !ok {
+		t.Error("expected /b to still be cached")
+	}
+	if _, ok := c.get("/c"); !ok {
+		t.Error("expected /c to still be cached")
+	}
+}
+
+func TestLRUCacheGetPromotesRecency(t *testing.T) {
+	c := newLRUCache(2)
+	c.set("/a", &cacheEntry{})
+	c.set("/b", &cacheEntry{})
+
+	if _, ok := c.get("/a"); !ok {
+		t.Fatal("expected /a to be cached")
+	}
+	c.set("/c", &cacheEntry{})
+
+	if _, ok := c.get("/a"); !ok {
+		t.Error("expected /a to survive eviction after being recently accessed")
+	}
+	if _, ok := c.get("/b"); ok {
+		t.Error("expected /b to be evicted as the least recently used entry")
+	}
+}
+
+func TestLRUCacheInvalidatePrefix(t *testing.T) {
+	c := newLRUCache(10)
+	c.set("/items", &cacheEntry{})
+	c.set("/items?limit=10", &cacheEntry{})
+	c.set("/search?keyword=shirt", &cacheEntry{})
+
+	c.invalidatePrefix("/items")
+
+	if _, ok := c.get("/items"); ok {
+		t.Error("expected /items to be invalidated")
+	}
+	if _, ok := c.get("/items?limit=10"); ok {
+		t.Error("expected /items?limit=10 to be invalidated")
+	}
+	if _, ok := c.get("/search?keyword=shirt"); !ok {
+		t.Error("expected /search entry to be unaffected")
+	}
+}
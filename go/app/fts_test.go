@@ -0,0 +1,22 @@
+package main
+
+import "testing"
+
+func TestFtsQuery(t *testing.T) {
+	cases := []struct {
+		name    string
+		keyword string
+		want    string
+	}{
+		{"simple keyword", "shirt", `"shirt"*`},
+		{"internal double quote is doubled", `foo"bar`, `"foo""bar"*`},
+		{"unicode keyword", "コート", `"コート"*`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := ftsQuery(tc.keyword); got != tc.want {
+				t.Errorf("ftsQuery(%q) = %q, want %q", tc.keyword, got, tc.want)
+			}
+		})
+	}
+}
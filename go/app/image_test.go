@@ -0,0 +1,28 @@
+package main
+
+import (
+	"image"
+	"testing"
+)
+
+func TestValidateImageConfig(t *testing.T) {
+	cases := []struct {
+		name    string
+		cfg     image.Config
+		wantErr bool
+	}{
+		{"well within limits", image.Config{Width: 800, Height: 600}, false},
+		{"width exceeds the dimension cap", image.Config{Width: maxImageDimension + 1, Height: 100}, true},
+		{"height exceeds the dimension cap", image.Config{Width: 100, Height: maxImageDimension + 1}, true},
+		{"pixel count exceeds the cap despite each dimension being under it", image.Config{Width: 4000, Height: 4000}, true},
+		{"at the dimension cap on both axes", image.Config{Width: maxImageDimension, Height: maxImageDimension}, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateImageConfig(tc.cfg)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("validateImageConfig(%+v) error = %v, wantErr %v", tc.cfg, err, tc.wantErr)
+			}
+		})
+	}
+}
@@ -1,155 +1,713 @@
 package main
 
 import (
+	"bytes"
+	"container/list"
 	"crypto/sha256"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"image"
+	"image/jpeg"
+	_ "image/png"
 	"io"
-	"io/ioutil"
 	"log"
+	"mime/multipart"
 	"net/http"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gorilla/mux"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/nfnt/resize"
 )
 
+const dbFile = "mercari.sqlite3"
+
+// Uploaded images are rejected above this size and re-encoded as JPEG at
+// this quality for everything else.
+const (
+	maxImageDimension = 4096
+	maxImagePixels    = 1e7
+	jpegQuality       = 85
+)
+
+var errImageTooLarge = errors.New("image dimensions exceed the allowed maximum")
+
+// validateImageConfig rejects images whose decoded dimensions are above the
+// configured cap, before we ever decode the full pixel data.
+func validateImageConfig(cfg image.Config) error {
+	if cfg.Width > maxImageDimension || cfg.Height > maxImageDimension || cfg.Width*cfg.Height > maxImagePixels {
+		return errImageTooLarge
+	}
+	return nil
+}
+
+// thumbnailSizes are the long-edge pixel sizes generated for every upload,
+// served back via GET /image/{hash}?size=<n>.
+var thumbnailSizes = []int{96, 256, 512}
+
+// Per-part size limits enforced while streaming a multipart upload.
+const (
+	maxImageUploadBytes = 50 << 20
+	maxFieldBytes       = 1 << 10
+)
+
+var errPayloadTooLarge = errors.New("payload too large")
+
+// readFormField reads a non-file multipart part into a string, capped at
+// maxFieldBytes so a malicious "name"/"category" field can't exhaust memory.
+func readFormField(part *multipart.Part) (string, error) {
+	limited := &io.LimitedReader{R: part, N: maxFieldBytes + 1}
+	data, err := io.ReadAll(limited)
+	if err != nil {
+		return "", err
+	}
+	if len(data) > maxFieldBytes {
+		return "", errPayloadTooLarge
+	}
+	return string(data), nil
+}
+
+// streamImagePart copies an image part straight to a temp file under dir,
+// hashing it as it goes via io.MultiWriter so the image is never buffered
+// whole in memory. The caller is responsible for removing the returned temp
+// file once it's done with it.
+func streamImagePart(dir string, part *multipart.Part) (path string, sum [sha256.Size]byte, err error) {
+	tmp, err := os.CreateTemp(dir, "upload-*.tmp")
+	if err != nil {
+		return "", sum, err
+	}
+	defer tmp.Close()
+
+	hasher := sha256.New()
+	limited := &io.LimitedReader{R: part, N: maxImageUploadBytes + 1}
+	written, err := io.Copy(io.MultiWriter(tmp, hasher), limited)
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", sum, err
+	}
+	if written > maxImageUploadBytes {
+		os.Remove(tmp.Name())
+		return "", sum, errPayloadTooLarge
+	}
+
+	copy(sum[:], hasher.Sum(nil))
+	return tmp.Name(), sum, nil
+}
+
+// responseCache holds rendered GET responses keyed by request URL so
+// repeated /items and /search calls skip re-serializing from SQLite.
+var responseCache = newLRUCache(128)
+
+// db is the shared SQLite handle. Writes are serialized with mu since
+// go-sqlite3 connections don't handle concurrent writers gracefully.
+var (
+	db *sql.DB
+	mu sync.Mutex
+)
+
+// ftsAvailable is set once at startup by migrate. go-sqlite3 only compiles
+// in FTS5 when built with -tags sqlite_fts5 (or the vendored libsqlite3
+// already has it); when it's missing we fall back to a LIKE-based search
+// instead of failing to start.
+var ftsAvailable bool
+
 type Item struct {
-	ID        string `json:"id"`                   // 商品のID
-	Name      string `json:"name"`                 // 商品の名前
-	Category  string `json:"category"`             // 商品のカテゴリ
-	ImageName string `json:"image_name,omitempty"` // 画像ファイル名
+	ID             int64  `json:"id"`                       // 商品のID
+	Name           string `json:"name"`                     // 商品の名前
+	Category       string `json:"category"`                 // 商品のカテゴリ名
+	ImageName      string `json:"image_name,omitempty"`     // 画像ファイル名
+	AvailableSizes []int  `json:"available_sizes,omitempty"` // 生成済みサムネイルのサイズ一覧
 }
 
 type Items struct {
 	Items []Item `json:"items"`
 }
 
+// cacheEntry is a rendered GET response kept in responseCache.
+type cacheEntry struct {
+	body    []byte
+	header  http.Header
+	etag    string
+	modTime time.Time
+}
+
+// lruCache is a fixed-capacity, least-recently-used cache of cacheEntry
+// values keyed by request URL.
+type lruCache struct {
+	mu       sync.Mutex
+	capacity int
+	ll       *list.List
+	items    map[string]*list.Element
+}
+
+type lruItem struct {
+	key   string
+	entry *cacheEntry
+}
+
+func newLRUCache(capacity int) *lruCache {
+	return &lruCache{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+func (c *lruCache) get(key string) (*cacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.ll.MoveToFront(el)
+	return el.Value.(*lruItem).entry, true
+}
+
+func (c *lruCache) set(key string, entry *cacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		el.Value.(*lruItem).entry = entry
+		c.ll.MoveToFront(el)
+		return
+	}
+	el := c.ll.PushFront(&lruItem{key: key, entry: entry})
+	c.items[key] = el
+	if c.ll.Len() > c.capacity {
+		oldest := c.ll.Back()
+		if oldest != nil {
+			c.ll.Remove(oldest)
+			delete(c.items, oldest.Value.(*lruItem).key)
+		}
+	}
+}
+
+// invalidatePrefix drops every cached entry whose URL starts with prefix,
+// e.g. "/items" or "/search", regardless of query string.
+func (c *lruCache) invalidatePrefix(prefix string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, el := range c.items {
+		if strings.HasPrefix(key, prefix) {
+			c.ll.Remove(el)
+			delete(c.items, key)
+		}
+	}
+}
+
+// responseBuffer is a minimal http.ResponseWriter that buffers a handler's
+// output in memory so withCache can hash and cache it before it reaches the
+// real client connection.
+type responseBuffer struct {
+	header http.Header
+	body   bytes.Buffer
+	status int
+}
+
+func newResponseBuffer() *responseBuffer {
+	return &responseBuffer{header: make(http.Header)}
+}
+
+func (b *responseBuffer) Header() http.Header { return b.header }
+
+func (b *responseBuffer) Write(p []byte) (int, error) {
+	if b.status == 0 {
+		b.status = http.StatusOK
+	}
+	return b.body.Write(p)
+}
+
+func (b *responseBuffer) WriteHeader(status int) {
+	if b.status == 0 {
+		b.status = status
+	}
+}
+
+func (b *responseBuffer) statusCode() int {
+	if b.status == 0 {
+		return http.StatusOK
+	}
+	return b.status
+}
+
+// withCache wraps a GET handler with an ETag/Last-Modified cache backed by
+// responseCache. On a cache hit it either answers 304 Not Modified or
+// replays the buffered body; on a miss it records the handler's response,
+// hashing the body with a TeeReader so the same read fills both the hash
+// and the discarded verification copy.
+func withCache(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.URL.String()
+
+		if entry, ok := responseCache.get(key); ok {
+			if notModified(r, entry) {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			writeCached(w, entry)
+			return
+		}
+
+		rec := newResponseBuffer()
+		next(rec, r)
+		if rec.statusCode() != http.StatusOK {
+			for k, v := range rec.Header() {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(rec.statusCode())
+			w.Write(rec.body.Bytes())
+			return
+		}
+
+		body := rec.body.Bytes()
+		hash := sha256.New()
+		if _, err := io.Copy(io.Discard, io.TeeReader(bytes.NewReader(body), hash)); err != nil {
+			http.Error(w, "Failed to cache response", http.StatusInternalServerError)
+			return
+		}
+
+		entry := &cacheEntry{
+			body:    body,
+			header:  rec.Header().Clone(),
+			etag:    fmt.Sprintf(`"%x"`, hash.Sum(nil)),
+			modTime: time.Now(),
+		}
+		responseCache.set(key, entry)
+		writeCached(w, entry)
+	}
+}
+
+// notModified reports whether the request's conditional headers match
+// entry, meaning the client already has the current representation.
+func notModified(r *http.Request, entry *cacheEntry) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return inm == entry.etag
+	}
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" {
+		if t, err := http.ParseTime(ims); err == nil {
+			return !entry.modTime.Truncate(time.Second).After(t)
+		}
+	}
+	return false
+}
+
+func writeCached(w http.ResponseWriter, entry *cacheEntry) {
+	for k, v := range entry.header {
+		w.Header()[k] = v
+	}
+	w.Header().Set("ETag", entry.etag)
+	w.Header().Set("Last-Modified", entry.modTime.UTC().Format(http.TimeFormat))
+	w.Write(entry.body)
+}
+
 func main() {
+	var err error
+	db, err = sql.Open("sqlite3", dbFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	if err := migrate(db); err != nil {
+		log.Fatal(err)
+	}
+
 	r := mux.NewRouter()
 
 	// 商品の一覧を取得するエンドポイント (GET)
-	r.HandleFunc("/items", getItemsHandler).Methods("GET")
+	r.HandleFunc("/items", withCache(getItemsHandler)).Methods("GET")
 	// 商品を追加するエンドポイント (POST)
 	r.HandleFunc("/items", postItemsHandler).Methods("POST")
 
 	// 新しいエンドポイント: 商品の詳細を取得
-	r.HandleFunc("/items/{item_id}", getItemHandler).Methods("GET")
-	r.HandleFunc("/search", searchItemsHandler).Methods("GET")
+	r.HandleFunc("/items/{item_id}", withCache(getItemHandler)).Methods("GET")
+	r.HandleFunc("/search", withCache(searchItemsHandler)).Methods("GET")
+	// アップロード画像・サムネイルを配信するエンドポイント
+	r.HandleFunc("/image/{hash}", getImageHandler).Methods("GET")
 
 	http.Handle("/", r)
 	log.Fatal(http.ListenAndServe(":8080", nil))
 }
 
+// migrate creates the tables used for item storage, plus the FTS5 index for
+// keyword search if this build of go-sqlite3 has FTS5 compiled in.
+func migrate(db *sql.DB) error {
+	stmts := []string{
+		`CREATE TABLE IF NOT EXISTS categories (
+			id   INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT UNIQUE NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS items (
+			id          INTEGER PRIMARY KEY AUTOINCREMENT,
+			name        TEXT NOT NULL,
+			category_id INTEGER NOT NULL,
+			image_name  TEXT,
+			FOREIGN KEY (category_id) REFERENCES categories(id)
+		)`,
+	}
+	for _, stmt := range stmts {
+		if _, err := db.Exec(stmt); err != nil {
+			return fmt.Errorf("migrate: %w", err)
+		}
+	}
+	// items predates the thumbnail_sizes column, so add it defensively
+	// rather than baking it into the CREATE TABLE above.
+	if _, err := db.Exec(`ALTER TABLE items ADD COLUMN thumbnail_sizes TEXT`); err != nil &&
+		!strings.Contains(err.Error(), "duplicate column name") {
+		return fmt.Errorf("migrate: %w", err)
+	}
+
+	// go-sqlite3 only registers the fts5 module when built with
+	// -tags sqlite_fts5. Rather than failing to start on a default build,
+	// fall back to LIKE-based search (see searchItemsHandler) when it's
+	// unavailable.
+	_, err := db.Exec(`CREATE VIRTUAL TABLE IF NOT EXISTS items_fts USING fts5(
+		id UNINDEXED,
+		name,
+		category_name
+	)`)
+	switch {
+	case err == nil:
+		ftsAvailable = true
+	case strings.Contains(err.Error(), "no such module: fts5"):
+		log.Println("warning: sqlite3 built without fts5 (build with -tags sqlite_fts5 to enable it); falling back to LIKE search")
+	default:
+		return fmt.Errorf("migrate: %w", err)
+	}
+	return nil
+}
+
+// parseSizes turns the comma-separated thumbnail_sizes column back into the
+// list of long-edge pixel sizes available for an item.
+func parseSizes(s string) []int {
+	if s == "" {
+		return nil
+	}
+	parts := strings.Split(s, ",")
+	sizes := make([]int, 0, len(parts))
+	for _, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			continue
+		}
+		sizes = append(sizes, n)
+	}
+	return sizes
+}
+
+// formatSizes is the inverse of parseSizes, used when writing the column.
+func formatSizes(sizes []int) string {
+	parts := make([]string, len(sizes))
+	for i, n := range sizes {
+		parts[i] = strconv.Itoa(n)
+	}
+	return strings.Join(parts, ",")
+}
+
 func getItemsHandler(w http.ResponseWriter, r *http.Request) {
 	// 商品一覧を返す処理
-	data, err := ioutil.ReadFile("items.json")
+	rows, err := db.Query(`
+		SELECT items.id, items.name, categories.name, items.image_name, items.thumbnail_sizes
+		FROM items
+		JOIN categories ON categories.id = items.category_id
+		ORDER BY items.id`)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	defer rows.Close()
+
+	var items Items
+	for rows.Next() {
+		var item Item
+		var imageName, sizes sql.NullString
+		if err := rows.Scan(&item.ID, &item.Name, &item.Category, &imageName, &sizes); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		item.ImageName = imageName.String
+		item.AvailableSizes = parseSizes(sizes.String)
+		items.Items = append(items.Items, item)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	w.Write(data)
+	json.NewEncoder(w).Encode(items)
+}
+
+// categoryID returns the id of the category with the given name, creating it
+// first if it doesn't exist yet. It must be called within tx so the
+// find-or-create is atomic with the item insert.
+func categoryID(tx *sql.Tx, name string) (int64, error) {
+	var id int64
+	err := tx.QueryRow("SELECT id FROM categories WHERE name = ?", name).Scan(&id)
+	if err == sql.ErrNoRows {
+		res, err := tx.Exec("INSERT INTO categories (name) VALUES (?)", name)
+		if err != nil {
+			return 0, err
+		}
+		return res.LastInsertId()
+	}
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// saveJPEG re-encodes img as a size-optimized JPEG and writes it to path.
+func saveJPEG(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return jpeg.Encode(f, img, &jpeg.Options{Quality: jpegQuality})
+}
+
+// isThumbnailSize reports whether size is one of the sizes we actually
+// generate, the same set thumbnailSizes drives at upload time.
+func isThumbnailSize(size int) bool {
+	for _, s := range thumbnailSizes {
+		if s == size {
+			return true
+		}
+	}
+	return false
+}
+
+// imageHashPattern matches the filenames saveJPEG writes: the hex-encoded
+// SHA-256 of the upload, followed by ".jpg" (see hashedFilename below).
+var imageHashPattern = regexp.MustCompile(`^[0-9a-f]{64}\.jpg$`)
+
+// getImageHandler serves the original image at images/<hash>.jpg, or, when
+// ?size=<n> is given, the matching thumbnail under images/thumbs/<n>/. hash
+// must match imageHashPattern and size must be one of thumbnailSizes: both
+// are used to build a filesystem path, so an unvalidated value would let a
+// caller read arbitrary files on the host.
+func getImageHandler(w http.ResponseWriter, r *http.Request) {
+	hash := mux.Vars(r)["hash"]
+	if !imageHashPattern.MatchString(hash) {
+		http.Error(w, "Invalid image hash", http.StatusBadRequest)
+		return
+	}
+	path := filepath.Join("images", hash)
+	if raw := r.URL.Query().Get("size"); raw != "" {
+		size, err := strconv.Atoi(raw)
+		if err != nil || !isThumbnailSize(size) {
+			http.Error(w, "Invalid size", http.StatusBadRequest)
+			return
+		}
+		path = filepath.Join("images", "thumbs", strconv.Itoa(size), hash)
+	}
+	http.ServeFile(w, r, path)
 }
 
 func postItemsHandler(w http.ResponseWriter, r *http.Request) {
-	// Multipart Formのパース
-	err := r.ParseMultipartForm(10 << 20) // 最大10MB
+	// multipart.Readerで逐次パースし、画像を丸ごとメモリに載せない
+	reader, err := r.MultipartReader()
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	// JSONデータの処理
-	var item Item
-	item.Name = r.FormValue("name")
-	item.Category = r.FormValue("category")
-	// 商品情報にIDを割り当てる（ここでは現在のタイムスタンプを使用）
-	item.ID = strconv.FormatInt(time.Now().UnixNano(), 10)
+	var name, category, tmpPath string
+	var hash [sha256.Size]byte
+	var tmpPaths []string
+	defer func() {
+		for _, p := range tmpPaths {
+			os.Remove(p)
+		}
+	}()
 
-	// 画像の処理
-	file, header, err := r.FormFile("image")
-	if err != nil {
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if part.FormName() == "image" {
+			if tmpPath != "" {
+				part.Close()
+				http.Error(w, "Only one image part is allowed", http.StatusBadRequest)
+				return
+			}
+			tmpPath, hash, err = streamImagePart("images", part)
+			if tmpPath != "" {
+				tmpPaths = append(tmpPaths, tmpPath)
+			}
+			part.Close()
+		} else {
+			var value string
+			value, err = readFormField(part)
+			part.Close()
+			switch part.FormName() {
+			case "name":
+				name = value
+			case "category":
+				category = value
+			}
+		}
+		if err == errPayloadTooLarge {
+			http.Error(w, "Upload exceeds the allowed size", http.StatusRequestEntityTooLarge)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if tmpPath == "" {
 		http.Error(w, "Image is required", http.StatusBadRequest)
 		return
 	}
-	defer file.Close()
+	hashedFilename := fmt.Sprintf("%x.jpg", hash)
 
-	// ハッシュ値を計算する
-	hash := sha256.New()
-	if _, err := io.Copy(hash, file); err != nil {
-		http.Error(w, "Failed to hash the image", http.StatusInternalServerError)
+	tmpFile, err := os.Open(tmpPath)
+	if err != nil {
+		http.Error(w, "Failed to read the image", http.StatusInternalServerError)
 		return
 	}
-	hashedFilename := fmt.Sprintf("%x", hash.Sum(nil)) + filepath.Ext(header.Filename)
-	item.ImageName = hashedFilename // 商品情報に画像ファイル名を追加
+	defer tmpFile.Close()
 
-	// ファイルポインタをリセットする
-	file.Seek(0, io.SeekStart)
+	cfg, _, err := image.DecodeConfig(tmpFile)
+	if err != nil {
+		http.Error(w, "Unsupported or invalid image", http.StatusBadRequest)
+		return
+	}
+	if err := validateImageConfig(cfg); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, err := tmpFile.Seek(0, io.SeekStart); err != nil {
+		http.Error(w, "Failed to read the image", http.StatusInternalServerError)
+		return
+	}
 
-	// ハッシュ化されたファイル名で画像を保存する
-	dst, err := os.Create(filepath.Join("images", hashedFilename))
+	img, _, err := image.Decode(tmpFile)
 	if err != nil {
-		http.Error(w, "Failed to save the image", http.StatusInternalServerError)
+		http.Error(w, "Failed to decode the image", http.StatusInternalServerError)
 		return
 	}
-	defer dst.Close()
 
-	if _, err := io.Copy(dst, file); err != nil {
+	if err := saveJPEG(filepath.Join("images", hashedFilename), img); err != nil {
 		http.Error(w, "Failed to save the image", http.StatusInternalServerError)
 		return
 	}
 
-	// 商品情報をJSONファイルに保存する処理
-	var items Items
-	data, err := ioutil.ReadFile("items.json")
-	if err == nil {
-		json.Unmarshal(data, &items)
+	for _, size := range thumbnailSizes {
+		thumb := resize.Thumbnail(uint(size), uint(size), img, resize.Lanczos3)
+		dir := filepath.Join("images", "thumbs", strconv.Itoa(size))
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			http.Error(w, "Failed to save thumbnails", http.StatusInternalServerError)
+			return
+		}
+		if err := saveJPEG(filepath.Join(dir, hashedFilename), thumb); err != nil {
+			http.Error(w, "Failed to save thumbnails", http.StatusInternalServerError)
+			return
+		}
 	}
-	items.Items = append(items.Items, item)
-	updatedData, err := json.Marshal(items)
+	thumbnailSizesCSV := formatSizes(thumbnailSizes)
+
+	// 商品情報をDBに保存する処理。go-sqlite3は同時書き込みに弱いのでmuで直列化する
+	mu.Lock()
+	defer mu.Unlock()
+
+	tx, err := db.Begin()
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	ioutil.WriteFile("items.json", updatedData, 0644)
 
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(map[string]string{"message": "item received: " + item.Name})
-}
-
-func getItemHandler(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	itemID := vars["item_id"] // URLパスからアイテムIDを取得
+	catID, err := categoryID(tx, category)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
-	// items.json ファイルから商品情報を読み込む
-	var items Items
-	data, err := ioutil.ReadFile("items.json")
+	res, err := tx.Exec(
+		"INSERT INTO items (name, category_id, image_name, thumbnail_sizes) VALUES (?, ?, ?, ?)",
+		name, catID, hashedFilename, thumbnailSizesCSV,
+	)
 	if err != nil {
+		tx.Rollback()
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	if err := json.Unmarshal(data, &items); err != nil {
+	itemID, err := res.LastInsertId()
+	if err != nil {
+		tx.Rollback()
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	// アイテムIDに一致する商品を検索
-	for _, item := range items.Items {
-		if item.ID == itemID {
-			w.Header().Set("Content-Type", "application/json")
-			if err := json.NewEncoder(w).Encode(item); err != nil {
-				http.Error(w, "Failed to encode item", http.StatusInternalServerError)
-			}
+	if ftsAvailable {
+		if _, err := tx.Exec(
+			"INSERT INTO items_fts (id, name, category_name) VALUES (?, ?, ?)",
+			itemID, name, category,
+		); err != nil {
+			tx.Rollback()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 	}
 
-	// アイテムが見つからない場合は404エラーを返す
-	http.NotFound(w, r)
+	if err := tx.Commit(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// 新しい商品が追加されたので一覧・検索のキャッシュを破棄する
+	responseCache.invalidatePrefix("/items")
+	responseCache.invalidatePrefix("/search")
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]string{"message": "item received: " + name})
+}
+
+func getItemHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	itemID, err := strconv.ParseInt(vars["item_id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid item id", http.StatusBadRequest)
+		return
+	}
+
+	var item Item
+	var imageName, sizes sql.NullString
+	err = db.QueryRow(`
+		SELECT items.id, items.name, categories.name, items.image_name, items.thumbnail_sizes
+		FROM items
+		JOIN categories ON categories.id = items.category_id
+		WHERE items.id = ?`, itemID).Scan(&item.ID, &item.Name, &item.Category, &imageName, &sizes)
+	if err == sql.ErrNoRows {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	item.ImageName = imageName.String
+	item.AvailableSizes = parseSizes(sizes.String)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(item); err != nil {
+		http.Error(w, "Failed to encode item", http.StatusInternalServerError)
+	}
 }
 
 func searchItemsHandler(w http.ResponseWriter, r *http.Request) {
@@ -160,24 +718,43 @@ func searchItemsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// items.json ファイルから商品情報を読み込む
-	var items Items
-	data, err := ioutil.ReadFile("items.json")
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+	// FTS5が使えるビルドでは全文検索、そうでなければLIKEでフォールバックする
+	var rows *sql.Rows
+	var err error
+	if ftsAvailable {
+		rows, err = db.Query(`
+			SELECT items.id, items.name, categories.name, items.image_name, items.thumbnail_sizes
+			FROM items_fts
+			JOIN items ON items.id = items_fts.id
+			JOIN categories ON categories.id = items.category_id
+			WHERE items_fts MATCH ?
+			ORDER BY items.id`, ftsQuery(keyword))
+	} else {
+		like := "%" + keyword + "%"
+		rows, err = db.Query(`
+			SELECT items.id, items.name, categories.name, items.image_name, items.thumbnail_sizes
+			FROM items
+			JOIN categories ON categories.id = items.category_id
+			WHERE items.name LIKE ? OR categories.name LIKE ?
+			ORDER BY items.id`, like, like)
 	}
-	if err := json.Unmarshal(data, &items); err != nil {
+	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	defer rows.Close()
 
-	// キーワードに一致する商品を検索
 	var matchedItems []Item
-	for _, item := range items.Items {
-		if strings.Contains(item.Name, keyword) || strings.Contains(item.Category, keyword) {
-			matchedItems = append(matchedItems, item)
+	for rows.Next() {
+		var item Item
+		var imageName, sizes sql.NullString
+		if err := rows.Scan(&item.ID, &item.Name, &item.Category, &imageName, &sizes); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
 		}
+		item.ImageName = imageName.String
+		item.AvailableSizes = parseSizes(sizes.String)
+		matchedItems = append(matchedItems, item)
 	}
 
 	// 検索結果を JSON でレスポンス
@@ -186,3 +763,12 @@ func searchItemsHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to encode items", http.StatusInternalServerError)
 	}
 }
+
+// ftsQuery turns a raw keyword into an FTS5 MATCH query that matches it as a
+// prefix against any indexed column. Wrapping it in double quotes (with
+// internal quotes doubled) treats it as a literal phrase rather than FTS5
+// query syntax.
+func ftsQuery(keyword string) string {
+	escaped := strings.ReplaceAll(keyword, `"`, `""`)
+	return `"` + escaped + `"*`
+}
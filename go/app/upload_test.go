@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"mime/multipart"
+	"os"
+	"testing"
+)
+
+// makePart builds a single multipart.Part carrying data, as either a form
+// field (filename == "") or a file part.
+func makePart(t *testing.T, fieldname, filename string, data []byte) *multipart.Part {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+
+	var fw io.Writer
+	var err error
+	if filename != "" {
+		fw, err = w.CreateFormFile(fieldname, filename)
+	} else {
+		fw, err = w.CreateFormField(fieldname)
+	}
+	if err != nil {
+		t.Fatalf("create part: %v", err)
+	}
+	if _, err := fw.Write(data); err != nil {
+		t.Fatalf("write part: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("close writer: %v", err)
+	}
+
+	part, err := multipart.NewReader(&buf, w.Boundary()).NextPart()
+	if err != nil {
+		t.Fatalf("read part: %v", err)
+	}
+	return part
+}
+
+func TestReadFormField(t *testing.T) {
+	t.Run("within the limit", func(t *testing.T) {
+		part := makePart(t, "name", "", []byte("t-shirt"))
+		got, err := readFormField(part)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got != "t-shirt" {
+			t.Errorf("got %q, want %q", got, "t-shirt")
+		}
+	})
+
+	t.Run("exceeds the limit", func(t *testing.T) {
+		big := bytes.Repeat([]byte("a"), maxFieldBytes+1)
+		part := makePart(t, "name", "", big)
+		if _, err := readFormField(part); err != errPayloadTooLarge {
+			t.Fatalf("got %v, want errPayloadTooLarge", err)
+		}
+	})
+}
+
+func TestStreamImagePart(t *testing.T) {
+	t.Run("within the limit writes a temp file and returns its hash", func(t *testing.T) {
+		dir := t.TempDir()
+		data := []byte("fake-image-bytes")
+		part := makePart(t, "image", "photo.jpg", data)
+
+		path, sum, err := streamImagePart(dir, part)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer os.Remove(path)
+
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("reading temp file: %v", err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Errorf("temp file contents = %q, want %q", got, data)
+		}
+		if want := sha256.Sum256(data); sum != want {
+			t.Errorf("hash = %x, want %x", sum, want)
+		}
+	})
+
+	t.Run("exceeds the limit returns errPayloadTooLarge and leaves no temp file behind", func(t *testing.T) {
+		dir := t.TempDir()
+		big := bytes.Repeat([]byte("a"), maxImageUploadBytes+1)
+		part := makePart(t, "image", "photo.jpg", big)
+
+		if _, _, err := streamImagePart(dir, part); err != errPayloadTooLarge {
+			t.Fatalf("got %v, want errPayloadTooLarge", err)
+		}
+
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatalf("reading temp dir: %v", err)
+		}
+		if len(entries) != 0 {
+			t.Errorf("temp file was not cleaned up, dir contains %v", entries)
+		}
+	})
+}